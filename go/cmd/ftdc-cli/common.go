@@ -0,0 +1,173 @@
+package main
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mongodb/ftdc"
+)
+
+// rfc3339Milli is the timestamp layout used when printing sample times in
+// human-readable command output.
+const rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+// timeFilter restricts processing to samples whose timestamp falls within
+// an optional [start, end] range. A zero value for either bound means that
+// side is unbounded. It is shared by the export, query, and tail commands.
+type timeFilter struct {
+	start time.Time
+	end   time.Time
+}
+
+// parseTimeFilter builds a timeFilter from RFC3339 start/end flag values.
+// An empty string leaves the corresponding bound unset.
+func parseTimeFilter(startFlag, endFlag string) (timeFilter, error) {
+	var tf timeFilter
+
+	if startFlag != "" {
+		t, err := time.Parse(time.RFC3339, startFlag)
+		if err != nil {
+			return tf, err
+		}
+		tf.start = t
+	}
+
+	if endFlag != "" {
+		t, err := time.Parse(time.RFC3339, endFlag)
+		if err != nil {
+			return tf, err
+		}
+		tf.end = t
+	}
+
+	return tf, nil
+}
+
+// includes reports whether ts falls within the filter's bounds.
+func (tf timeFilter) includes(ts time.Time) bool {
+	if !tf.start.IsZero() && ts.Before(tf.start) {
+		return false
+	}
+	if !tf.end.IsZero() && ts.After(tf.end) {
+		return false
+	}
+	return true
+}
+
+// metricFilter matches dotted metric keys (e.g. serverStatus.wiredTiger.cache.*)
+// against a set of glob patterns. A nil or empty filter matches everything.
+type metricFilter struct {
+	globs []string
+}
+
+// newMetricFilter builds a metricFilter from a comma-separated --metrics flag
+// value. An empty string matches every metric.
+func newMetricFilter(raw string) metricFilter {
+	if raw == "" {
+		return metricFilter{}
+	}
+
+	var globs []string
+	for _, g := range strings.Split(raw, ",") {
+		g = strings.TrimSpace(g)
+		if g != "" {
+			globs = append(globs, g)
+		}
+	}
+
+	return metricFilter{globs: globs}
+}
+
+// matches reports whether key satisfies at least one of the filter's globs.
+func (f metricFilter) matches(key string) bool {
+	if len(f.globs) == 0 {
+		return true
+	}
+
+	for _, g := range f.globs {
+		if ok, _ := path.Match(g, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sampleTimestamp derives the wall-clock time of the i-th sample in chunk,
+// relying on the "start" metric that mongod stamps into every FTDC document
+// as milliseconds since the Unix epoch.
+func sampleTimestamp(c *ftdc.Chunk, i int) time.Time {
+	for _, m := range c.Metrics {
+		if m.Key() == "start" && i < len(m.Values) {
+			return time.UnixMilli(m.Values[i]).UTC()
+		}
+	}
+
+	return time.Time{}
+}
+
+// flattenSample collects the i-th value of every metric in chunk that
+// satisfies filter into a dotted-key document, suitable for JSON/JSONL
+// encoding or re-marshaling to BSON.
+func flattenSample(c *ftdc.Chunk, i int, filter metricFilter) map[string]interface{} {
+	doc := make(map[string]interface{}, len(c.Metrics))
+
+	for _, m := range c.Metrics {
+		key := m.Key()
+		if !filter.matches(key) {
+			continue
+		}
+		if i < len(m.Values) {
+			doc[key] = m.Values[i]
+		}
+	}
+
+	return doc
+}
+
+// replicaSetNameKey and shardNameKey are the field paths mongod stamps into
+// the FTDC metadata document once per file: the replica set name from
+// replSetGetStatus and, on a shard server, the shard name from
+// shardIdentity. Unlike the per-sample metrics (which FTDC stores as bare
+// numbers), the metadata document keeps its original BSON types, so these
+// are the only string-valued identifiers an FTDC file exposes.
+var (
+	replicaSetNameKey = []string{"replSetGetStatus", "set"}
+	shardNameKey      = []string{"shardIdentity", "shardName"}
+)
+
+// chunkIdentifierLabels extracts whichever of the replica-set and shard
+// identifiers are present in chunk's metadata document. A standalone
+// mongod, or one running without --configsvr/--shardsvr, yields neither.
+func chunkIdentifierLabels(c *ftdc.Chunk) map[string]string {
+	labels := map[string]string{}
+
+	meta := c.GetMetadata()
+	if meta == nil {
+		return labels
+	}
+
+	if s, ok := meta.RecursiveLookup(replicaSetNameKey...).StringValueOK(); ok {
+		labels["replica_set"] = s
+	}
+	if s, ok := meta.RecursiveLookup(shardNameKey...).StringValueOK(); ok {
+		labels["shard"] = s
+	}
+
+	return labels
+}
+
+// flattenSampleValues is flattenSample without a filter and with every
+// metric coerced to float64, which the query pipeline aggregates over.
+func flattenSampleValues(c *ftdc.Chunk, i int) map[string]float64 {
+	doc := make(map[string]float64, len(c.Metrics))
+
+	for _, m := range c.Metrics {
+		if i < len(m.Values) {
+			doc[m.Key()] = float64(m.Values[i])
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/evergreen-ci/birch"
+	"github.com/evergreen-ci/birch/bsontype"
+	"github.com/mongodb/ftdc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportStart   string
+	exportEnd     string
+	exportMetrics string
+	exportOutput  string
+	exportPretty  bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export FTDC data to JSON, JSONL, or BSON",
+	Long:  `Export metrics from an FTDC file to a format other than CSV.`,
+}
+
+var exportJSONCmd = &cobra.Command{
+	Use:   "json [ftdc-file]",
+	Short: "Export FTDC samples as a JSON array",
+	Long:  `Export metrics from an FTDC file as a single well-formed JSON array, one object per sample.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runExportJSON,
+}
+
+var exportJSONLCmd = &cobra.Command{
+	Use:   "jsonl [ftdc-file]",
+	Short: "Export FTDC samples as newline-delimited JSON",
+	Long:  `Export metrics from an FTDC file as one flattened JSON document per line.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runExportJSONL,
+}
+
+var exportBSONCmd = &cobra.Command{
+	Use:   "bson [ftdc-file]",
+	Short: "Export FTDC samples as BSON",
+	Long:  `Re-emit the decompressed samples of an FTDC file as a stream of BSON documents.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runExportBSON,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportJSONCmd, exportJSONLCmd, exportBSONCmd)
+
+	for _, cmd := range []*cobra.Command{exportJSONCmd, exportJSONLCmd, exportBSONCmd} {
+		cmd.Flags().StringVar(&exportStart, "start", "", "Only include samples at or after this RFC3339 timestamp")
+		cmd.Flags().StringVar(&exportEnd, "end", "", "Only include samples at or before this RFC3339 timestamp")
+		cmd.Flags().StringVar(&exportMetrics, "metrics", "", "Comma-separated glob patterns restricting which metrics are included")
+		cmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: stdout)")
+	}
+
+	exportJSONCmd.Flags().BoolVar(&exportPretty, "pretty", false, "Indent the JSON array output")
+}
+
+// exportSamples walks every chunk of inputFile and invokes emit once per
+// sample that passes tf and mf, in chunk order.
+func exportSamples(inputFile string, tf timeFilter, mf metricFilter, emit func(map[string]interface{}) error) error {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open FTDC file: %w", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	iter := ftdc.ReadChunks(ctx, f)
+
+	for iter.Next() {
+		chunk := iter.Chunk()
+		for i := 0; i < chunk.Size(); i++ {
+			ts := sampleTimestamp(chunk, i)
+			if !tf.includes(ts) {
+				continue
+			}
+
+			if err := emit(flattenSample(chunk, i, mf)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return iter.Err()
+}
+
+func openExportOutput() (*os.File, error) {
+	if exportOutput == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(exportOutput)
+}
+
+func runExportJSON(cmd *cobra.Command, args []string) error {
+	tf, err := parseTimeFilter(exportStart, exportEnd)
+	if err != nil {
+		return fmt.Errorf("invalid time filter: %w", err)
+	}
+	mf := newMetricFilter(exportMetrics)
+
+	out, err := openExportOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	if _, err := w.WriteString("["); err != nil {
+		return err
+	}
+
+	first := true
+	if err := exportSamples(args[0], tf, mf, func(doc map[string]interface{}) error {
+		if !first {
+			if _, err := w.WriteString(","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		var data []byte
+		var err error
+		if exportPretty {
+			data, err = json.MarshalIndent(doc, "", "  ")
+		} else {
+			data, err = json.Marshal(doc)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	_, err = w.WriteString("]\n")
+	return err
+}
+
+func runExportJSONL(cmd *cobra.Command, args []string) error {
+	tf, err := parseTimeFilter(exportStart, exportEnd)
+	if err != nil {
+		return fmt.Errorf("invalid time filter: %w", err)
+	}
+	mf := newMetricFilter(exportMetrics)
+
+	out, err := openExportOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	enc := json.NewEncoder(w)
+
+	return exportSamples(args[0], tf, mf, func(doc map[string]interface{}) error {
+		return enc.Encode(doc)
+	})
+}
+
+func runExportBSON(cmd *cobra.Command, args []string) error {
+	tf, err := parseTimeFilter(exportStart, exportEnd)
+	if err != nil {
+		return fmt.Errorf("invalid time filter: %w", err)
+	}
+	mf := newMetricFilter(exportMetrics)
+
+	out, err := openExportOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open FTDC file: %w", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	iter := ftdc.ReadChunks(ctx, f)
+
+	for iter.Next() {
+		chunk := iter.Chunk()
+
+		i := 0
+		it := chunk.StructuredIterator(ctx)
+		for it.Next() {
+			ts := sampleTimestamp(chunk, i)
+			i++
+
+			if !tf.includes(ts) {
+				continue
+			}
+
+			doc := it.Document()
+			pruneDocument(doc, mf)
+
+			data, err := doc.MarshalBSON()
+			if err != nil {
+				it.Close()
+				return fmt.Errorf("failed to marshal sample to BSON: %w", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				it.Close()
+				return err
+			}
+		}
+		if err := it.Err(); err != nil {
+			it.Close()
+			return fmt.Errorf("failed to decode samples: %w", err)
+		}
+		it.Close()
+	}
+
+	return iter.Err()
+}
+
+// pruneDocument removes the leaf fields of doc that don't match mf,
+// leaving containers (embedded documents and arrays) in place so any
+// matching descendants stay reachable. A filter with no globs is a
+// no-op, since it matches everything.
+func pruneDocument(doc *birch.Document, mf metricFilter) {
+	if len(mf.globs) == 0 {
+		return
+	}
+
+	keys, err := doc.Keys(true)
+	if err != nil {
+		return
+	}
+
+	var drop [][]string
+	for _, k := range keys {
+		path := append(append([]string{}, k.Prefix...), k.Name)
+
+		elem := doc.RecursiveLookupElement(path...)
+		if elem == nil {
+			continue
+		}
+		switch elem.Value().Type() {
+		case bsontype.EmbeddedDocument, bsontype.Array:
+			continue
+		}
+
+		if !mf.matches(strings.Join(path, ".")) {
+			drop = append(drop, path)
+		}
+	}
+
+	for _, path := range drop {
+		doc.Delete(path...)
+	}
+}
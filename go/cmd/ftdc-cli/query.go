@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mongodb/ftdc"
+	"github.com/spf13/cobra"
+)
+
+var queryExpr string
+
+var queryCmd = &cobra.Command{
+	Use:   "query [ftdc-file]",
+	Short: "Run a filter/aggregation pipeline over FTDC data",
+	Long: `Run a small Flux/InfluxQL-like pipeline expression against an FTDC file,
+distilling a multi-GB capture into a compact summary without exporting the
+full dataset first. Stages are separated by "|", for example:
+
+  ftdc-cli query file.ftdc --expr 'select(serverStatus.wiredTiger.cache.*) | window(1m) | mean() | topk(10)'
+
+where() predicates compare a metric value against a number, e.g.
+"where(serverStatus.connections.current > 100)", or compare the sample
+timestamp against an RFC3339 value using the special key "time", e.g.
+"where(time >= 2024-01-02T15:04:05Z)".`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQuery,
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringVar(&queryExpr, "expr", "", "Pipeline expression to evaluate (required)")
+	queryCmd.MarkFlagRequired("expr")
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	stages, err := parsePipeline(queryExpr)
+	if err != nil {
+		return fmt.Errorf("invalid --expr: %w", err)
+	}
+
+	samples, errc := readSamples(args[0])
+
+	ch := samples
+	for _, s := range stages {
+		ch = s(ch)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+
+	for sample := range ch {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+
+	return <-errc
+}
+
+// Sample is the unit of work passed between query pipeline stages: a
+// timestamp plus the set of metric values live at that point.
+type Sample struct {
+	Time    time.Time          `json:"time"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// readSamples streams every sample of inputFile into a channel in chunk
+// order. The returned error channel receives exactly one value (nil on
+// success) once the sample channel has been closed.
+func readSamples(inputFile string) (<-chan Sample, <-chan error) {
+	out := make(chan Sample)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+
+		f, err := os.Open(inputFile)
+		if err != nil {
+			errc <- fmt.Errorf("failed to open FTDC file: %w", err)
+			return
+		}
+		defer f.Close()
+
+		ctx := context.Background()
+		iter := ftdc.ReadChunks(ctx, f)
+
+		for iter.Next() {
+			chunk := iter.Chunk()
+			for i := 0; i < chunk.Size(); i++ {
+				out <- Sample{
+					Time:    sampleTimestamp(chunk, i),
+					Metrics: flattenSampleValues(chunk, i),
+				}
+			}
+		}
+
+		errc <- iter.Err()
+	}()
+
+	return out, errc
+}
+
+// stage is a single pipeline step: it consumes upstream samples and
+// produces downstream ones, run concurrently with its neighbors.
+type stage func(<-chan Sample) <-chan Sample
+
+var stagePattern = regexp.MustCompile(`^(\w+)(?:\((.*)\))?$`)
+
+// parsePipeline hand-parses a "stage | stage | ..." expression into the
+// stage funcs that implement it, in order.
+func parsePipeline(expr string) ([]stage, error) {
+	parts := strings.Split(expr, "|")
+	stages := make([]stage, 0, len(parts))
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty pipeline stage")
+		}
+
+		m := stagePattern.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("malformed stage: %q", part)
+		}
+
+		s, err := buildStage(m[1], strings.TrimSpace(m[2]))
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", part, err)
+		}
+
+		stages = append(stages, s)
+	}
+
+	return stages, nil
+}
+
+func buildStage(name, args string) (stage, error) {
+	switch name {
+	case "select":
+		filter := newMetricFilter(args)
+		return func(in <-chan Sample) <-chan Sample {
+			out := make(chan Sample)
+			go func() {
+				defer close(out)
+				for s := range in {
+					kept := make(map[string]float64, len(s.Metrics))
+					for k, v := range s.Metrics {
+						if filter.matches(k) {
+							kept[k] = v
+						}
+					}
+					out <- Sample{Time: s.Time, Metrics: kept}
+				}
+			}()
+			return out
+		}, nil
+
+	case "where":
+		pred, err := parsePredicate(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(in <-chan Sample) <-chan Sample {
+			out := make(chan Sample)
+			go func() {
+				defer close(out)
+				for s := range in {
+					if pred(s) {
+						out <- s
+					}
+				}
+			}()
+			return out
+		}, nil
+
+	case "window":
+		d, err := time.ParseDuration(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		return func(in <-chan Sample) <-chan Sample {
+			out := make(chan Sample)
+			go func() {
+				defer close(out)
+				for s := range in {
+					out <- Sample{Time: s.Time.Truncate(d), Metrics: s.Metrics}
+				}
+			}()
+			return out
+		}, nil
+
+	case "mean":
+		return groupStage(meanOf), nil
+	case "max":
+		return groupStage(maxOf), nil
+	case "min":
+		return groupStage(minOf), nil
+	case "p95":
+		return groupStage(p95Of), nil
+	case "delta":
+		return deltaStage(false), nil
+	case "rate":
+		return deltaStage(true), nil
+
+	case "topk":
+		n, err := strconv.Atoi(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count: %w", err)
+		}
+		return topkStage(n), nil
+	}
+
+	return nil, fmt.Errorf("unknown stage %q", name)
+}
+
+// groupStage buckets consecutive samples sharing the same Time (normally
+// produced by a preceding window() stage) and reduces each bucket's values
+// per metric with reduce.
+func groupStage(reduce func([]float64) float64) stage {
+	return func(in <-chan Sample) <-chan Sample {
+		out := make(chan Sample)
+		go func() {
+			defer close(out)
+
+			var bucketTime time.Time
+			values := map[string][]float64{}
+			haveBucket := false
+
+			flush := func() {
+				if !haveBucket {
+					return
+				}
+				metrics := make(map[string]float64, len(values))
+				for k, vs := range values {
+					metrics[k] = reduce(vs)
+				}
+				out <- Sample{Time: bucketTime, Metrics: metrics}
+			}
+
+			for s := range in {
+				if haveBucket && !s.Time.Equal(bucketTime) {
+					flush()
+					values = map[string][]float64{}
+				}
+				bucketTime = s.Time
+				haveBucket = true
+				for k, v := range s.Metrics {
+					values[k] = append(values[k], v)
+				}
+			}
+			flush()
+		}()
+		return out
+	}
+}
+
+// deltaStage emits the change in each metric between consecutive groups
+// produced by a preceding window()+aggregator pair. When rate is true the
+// change is divided by the elapsed time to produce a per-second rate.
+func deltaStage(rate bool) stage {
+	return func(in <-chan Sample) <-chan Sample {
+		out := make(chan Sample)
+		go func() {
+			defer close(out)
+
+			var prev *Sample
+			for s := range in {
+				s := s
+				if prev != nil {
+					elapsed := s.Time.Sub(prev.Time).Seconds()
+					metrics := make(map[string]float64, len(s.Metrics))
+					for k, v := range s.Metrics {
+						d := v - prev.Metrics[k]
+						if rate && elapsed > 0 {
+							d /= elapsed
+						}
+						metrics[k] = d
+					}
+					out <- Sample{Time: s.Time, Metrics: metrics}
+				}
+				prev = &s
+			}
+		}()
+		return out
+	}
+}
+
+// topkStage keeps, per sample, only the n metrics with the largest values.
+func topkStage(n int) stage {
+	return func(in <-chan Sample) <-chan Sample {
+		out := make(chan Sample)
+		go func() {
+			defer close(out)
+			for s := range in {
+				type kv struct {
+					key string
+					val float64
+				}
+				pairs := make([]kv, 0, len(s.Metrics))
+				for k, v := range s.Metrics {
+					pairs = append(pairs, kv{k, v})
+				}
+				sort.Slice(pairs, func(i, j int) bool { return pairs[i].val > pairs[j].val })
+				if n < len(pairs) {
+					pairs = pairs[:n]
+				}
+
+				metrics := make(map[string]float64, len(pairs))
+				for _, p := range pairs {
+					metrics[p.key] = p.val
+				}
+				out <- Sample{Time: s.Time, Metrics: metrics}
+			}
+		}()
+		return out
+	}
+}
+
+func meanOf(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
+
+func maxOf(vs []float64) float64 {
+	m := vs[0]
+	for _, v := range vs[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func minOf(vs []float64) float64 {
+	m := vs[0]
+	for _, v := range vs[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func p95Of(vs []float64) float64 {
+	sorted := append([]float64(nil), vs...)
+	sort.Float64s(sorted)
+	idx := int(float64(len(sorted)-1) * 0.95)
+	return sorted[idx]
+}
+
+var predicatePattern = regexp.MustCompile(`^([\w.\*]+)\s*(==|!=|>=|<=|>|<)\s*(\S+)$`)
+
+// parsePredicate compiles a "key op value" where() expression into a
+// function that tests a Sample. The special key "time" compares against
+// Sample.Time using an RFC3339 value instead of looking up a metric.
+func parsePredicate(expr string) (func(Sample) bool, error) {
+	m := predicatePattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return nil, fmt.Errorf("malformed predicate: %q", expr)
+	}
+
+	key, op, rawVal := m[1], m[2], m[3]
+
+	if key == "time" {
+		return parseTimePredicate(op, rawVal)
+	}
+
+	val, err := strconv.ParseFloat(rawVal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate value: %w", err)
+	}
+
+	cmp := map[string]func(a, b float64) bool{
+		"==": func(a, b float64) bool { return a == b },
+		"!=": func(a, b float64) bool { return a != b },
+		">=": func(a, b float64) bool { return a >= b },
+		"<=": func(a, b float64) bool { return a <= b },
+		">":  func(a, b float64) bool { return a > b },
+		"<":  func(a, b float64) bool { return a < b },
+	}[op]
+
+	return func(s Sample) bool {
+		v, ok := s.Metrics[key]
+		return ok && cmp(v, val)
+	}, nil
+}
+
+// parseTimePredicate builds the "time op value" form of parsePredicate,
+// comparing Sample.Time against an RFC3339 timestamp.
+func parseTimePredicate(op, rawVal string) (func(Sample) bool, error) {
+	t, err := time.Parse(time.RFC3339, rawVal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate time %q: %w", rawVal, err)
+	}
+
+	cmp := map[string]func(a, b time.Time) bool{
+		"==": func(a, b time.Time) bool { return a.Equal(b) },
+		"!=": func(a, b time.Time) bool { return !a.Equal(b) },
+		">=": func(a, b time.Time) bool { return !a.Before(b) },
+		"<=": func(a, b time.Time) bool { return !a.After(b) },
+		">":  func(a, b time.Time) bool { return a.After(b) },
+		"<":  func(a, b time.Time) bool { return a.Before(b) },
+	}[op]
+
+	return func(s Sample) bool {
+		return cmp(s.Time, t)
+	}, nil
+}
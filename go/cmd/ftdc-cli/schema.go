@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mongodb/ftdc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaDiff bool
+	schemaJSON bool
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [ftdc-file]",
+	Short: "Report the metric inventory and schema-change boundaries of an FTDC file",
+	Long: `Walk an FTDC file and print, per detected schema epoch, the chunk index
+range, start/end timestamps, sample count, and the full metric key list. A
+schema epoch is a contiguous run of chunks that all report the same set of
+metric keys; mongod starts a new epoch whenever the shape of serverStatus
+(or any other sampled document) changes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().BoolVar(&schemaDiff, "diff", false, "Show added/removed metric keys between consecutive epochs")
+	schemaCmd.Flags().BoolVar(&schemaJSON, "json", false, "Print epochs as JSON instead of human-readable text")
+}
+
+// schemaEpoch describes a contiguous run of chunks that share a metric key set.
+type schemaEpoch struct {
+	FirstChunk int       `json:"firstChunk"`
+	LastChunk  int       `json:"lastChunk"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Samples    int       `json:"samples"`
+	Keys       []string  `json:"keys"`
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open FTDC file: %w", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	iter := ftdc.ReadChunks(ctx, f)
+
+	var epochs []schemaEpoch
+	var prevKeys []string
+
+	for chunkIdx := 0; iter.Next(); chunkIdx++ {
+		chunk := iter.Chunk()
+		n := chunk.Size()
+
+		keys := make([]string, 0, len(chunk.Metrics))
+		for _, m := range chunk.Metrics {
+			keys = append(keys, m.Key())
+		}
+		sort.Strings(keys)
+
+		start := sampleTimestamp(chunk, 0)
+		end := start
+		if n > 0 {
+			end = sampleTimestamp(chunk, n-1)
+		}
+
+		if len(epochs) > 0 && keysEqual(prevKeys, keys) {
+			cur := &epochs[len(epochs)-1]
+			cur.LastChunk = chunkIdx
+			cur.End = end
+			cur.Samples += n
+		} else {
+			epochs = append(epochs, schemaEpoch{
+				FirstChunk: chunkIdx,
+				LastChunk:  chunkIdx,
+				Start:      start,
+				End:        end,
+				Samples:    n,
+				Keys:       keys,
+			})
+		}
+
+		prevKeys = keys
+	}
+
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to read chunks: %w", err)
+	}
+
+	if schemaJSON {
+		return printSchemaJSON(epochs)
+	}
+	printSchemaText(epochs)
+	return nil
+}
+
+func keysEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func printSchemaJSON(epochs []schemaEpoch) error {
+	type jsonEpoch struct {
+		schemaEpoch
+		Added   []string `json:"added,omitempty"`
+		Removed []string `json:"removed,omitempty"`
+	}
+
+	out := make([]jsonEpoch, len(epochs))
+	for i, e := range epochs {
+		out[i] = jsonEpoch{schemaEpoch: e}
+		if schemaDiff && i > 0 {
+			out[i].Added, out[i].Removed = diffKeys(epochs[i-1].Keys, e.Keys)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func printSchemaText(epochs []schemaEpoch) {
+	for i, e := range epochs {
+		fmt.Printf("epoch %d: chunks %d-%d, %s to %s, %d samples, %d metrics\n",
+			i, e.FirstChunk, e.LastChunk, e.Start.Format(rfc3339Milli), e.End.Format(rfc3339Milli), e.Samples, len(e.Keys))
+
+		if schemaDiff && i > 0 {
+			added, removed := diffKeys(epochs[i-1].Keys, e.Keys)
+			for _, k := range added {
+				fmt.Printf("  + %s\n", k)
+			}
+			for _, k := range removed {
+				fmt.Printf("  - %s\n", k)
+			}
+		} else {
+			for _, k := range e.Keys {
+				fmt.Printf("  %s\n", k)
+			}
+		}
+	}
+}
+
+// diffKeys reports which keys were added and removed going from "from" to
+// "to". Both slices must already be sorted.
+func diffKeys(from, to []string) (added, removed []string) {
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			i++
+			j++
+		case from[i] < to[j]:
+			removed = append(removed, from[i])
+			i++
+		default:
+			added = append(added, to[j])
+			j++
+		}
+	}
+	removed = append(removed, from[i:]...)
+	added = append(added, to[j:]...)
+	return added, removed
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	serveListen string
+	serveSource string
+	serveConfig string
+	serveLabels []string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Expose FTDC metrics as a Prometheus/OpenMetrics scrape endpoint",
+	Long: `Tail an FTDC directory (see "tail") and re-expose selected metrics as a
+Prometheus scrape endpoint, translating dotted BSON metric paths such as
+serverStatus.wiredTiger.cache.bytes_currently_in_the_cache into snake_case
+metric names. This lets operators scrape mongod's own diagnostic data
+instead of depending on a separately-running mongod-exporter.`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":9216", "Address to serve /metrics on")
+	serveCmd.Flags().StringVar(&serveSource, "source", "", "FTDC file or diagnostic.data directory to tail (required)")
+	serveCmd.Flags().StringVar(&serveConfig, "config", "", "YAML config with allow/deny globs and metric type hints")
+	serveCmd.Flags().StringArrayVar(&serveLabels, "label", nil, "Static label to attach to every metric, as key=value (e.g. --label rs=shard01)")
+	serveCmd.MarkFlagRequired("source")
+}
+
+// exporterConfig is the YAML schema accepted via --config.
+type exporterConfig struct {
+	Allow []string          `yaml:"allow"`
+	Deny  []string          `yaml:"deny"`
+	Types map[string]string `yaml:"types"` // glob -> "counter" or "gauge"
+}
+
+func loadExporterConfig(path string) (exporterConfig, error) {
+	var cfg exporterConfig
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// typeOf returns the configured Prometheus metric type for key, defaulting
+// to "gauge" since FTDC only stores raw numbers and has no counter/gauge
+// distinction of its own.
+func (c exporterConfig) typeOf(key string) string {
+	for pattern, typ := range c.Types {
+		if ok, _ := matchGlob(pattern, key); ok {
+			return typ
+		}
+	}
+	return "gauge"
+}
+
+func (c exporterConfig) permits(key string) bool {
+	allowed := len(c.Allow) == 0
+	for _, g := range c.Allow {
+		if ok, _ := matchGlob(g, key); ok {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+
+	for _, g := range c.Deny {
+		if ok, _ := matchGlob(g, key); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchGlob(pattern, key string) (bool, error) {
+	return newMetricFilter(pattern).matches(key), nil
+}
+
+// metricSnapshot holds the most recently observed value for every metric
+// key, plus whichever replica-set/shard identifier labels the tailer found
+// in the source file, guarded by mu so the HTTP handler can read it while
+// the tailer goroutine keeps writing to it.
+type metricSnapshot struct {
+	mu     sync.RWMutex
+	values map[string]float64
+	labels map[string]string
+}
+
+func newMetricSnapshot() *metricSnapshot {
+	return &metricSnapshot{values: map[string]float64{}, labels: map[string]string{}}
+}
+
+func (s *metricSnapshot) update(doc map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range doc {
+		switch val := v.(type) {
+		case int64:
+			s.values[k] = float64(val)
+		case string:
+			if k != "time" {
+				s.labels[k] = val
+			}
+		}
+	}
+}
+
+func (s *metricSnapshot) snapshot() (map[string]float64, map[string]string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make(map[string]float64, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	return values, labels
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := loadExporterConfig(serveConfig)
+	if err != nil {
+		return err
+	}
+
+	staticLabels, err := parseStaticLabels(serveLabels)
+	if err != nil {
+		return err
+	}
+
+	snap := newMetricSnapshot()
+
+	info, err := os.Stat(serveSource)
+	if err != nil {
+		return fmt.Errorf("failed to stat --source: %w", err)
+	}
+
+	errc := make(chan error, 1)
+	emit := tailEmitter(func(doc map[string]interface{}) error {
+		snap.update(doc)
+		return nil
+	})
+
+	// tailDirectory/tailFileForever resume across fsnotify write events
+	// rather than stalling after the first EOF, so snap keeps reflecting
+	// whatever mongod has most recently flushed.
+	go func() {
+		if info.IsDir() {
+			errc <- tailDirectory(serveSource, 0, metricFilter{}, emit)
+		} else {
+			errc <- tailFileForever(serveSource, metricFilter{}, emit)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		values, sampleLabels := snap.snapshot()
+
+		// Identifiers found in the samples are the default; an operator's
+		// explicit --label of the same key wins, since they said so on the
+		// command line.
+		labels := make(map[string]string, len(sampleLabels)+len(staticLabels))
+		for k, v := range sampleLabels {
+			labels[k] = v
+		}
+		for k, v := range staticLabels {
+			labels[k] = v
+		}
+
+		writeOpenMetrics(w, cfg, labels, values)
+	})
+
+	server := &http.Server{Addr: serveListen, Handler: mux}
+
+	go func() {
+		errc <- server.ListenAndServe()
+	}()
+
+	return <-errc
+}
+
+func parseStaticLabels(raw []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, kv := range raw {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --label %q, expected key=value", kv)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// translateMetricName turns a dotted FTDC metric path into a Prometheus
+// metric name plus the labels derived from any numeric array indices in
+// the path, e.g. "locks.Global.acquireCount.0" becomes
+// "ftdc_locks_global_acquire_count" with label index0="0". The replica-set
+// and shard identifiers that the tailer finds in the source file are
+// attached separately, as labels shared by every metric (see runServe).
+func translateMetricName(key string) (string, map[string]string) {
+	parts := strings.Split(key, ".")
+	labels := map[string]string{}
+	var nameParts []string
+
+	arrayIndex := 0
+	for _, p := range parts {
+		if n, err := strconv.Atoi(p); err == nil {
+			labels[fmt.Sprintf("index%d", arrayIndex)] = strconv.Itoa(n)
+			arrayIndex++
+			continue
+		}
+		nameParts = append(nameParts, toSnakeCase(p))
+	}
+
+	return "ftdc_" + strings.Join(nameParts, "_"), labels
+}
+
+func toSnakeCase(s string) string {
+	snake := camelBoundary.ReplaceAllString(s, "${1}_${2}")
+	return strings.ToLower(snake)
+}
+
+// writeOpenMetrics renders values as OpenMetrics text, with baseLabels
+// (sample-derived identifiers plus any operator --label flags) attached to
+// every metric alongside the array-index labels translateMetricName derives
+// from each key.
+func writeOpenMetrics(w http.ResponseWriter, cfg exporterConfig, baseLabels map[string]string, values map[string]float64) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if cfg.permits(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	declared := map[string]bool{}
+	for _, k := range keys {
+		name, labels := translateMetricName(k)
+		for lk, lv := range baseLabels {
+			labels[lk] = lv
+		}
+
+		if !declared[name] {
+			fmt.Fprintf(w, "# TYPE %s %s\n", name, cfg.typeOf(k))
+			declared[name] = true
+		}
+
+		fmt.Fprintf(w, "%s%s %v\n", name, formatLabels(labels), values[k])
+	}
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
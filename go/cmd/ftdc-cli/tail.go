@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mongodb/ftdc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tailFrom    string
+	tailFormat  string
+	tailMetrics string
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail [file-or-dir]",
+	Short: "Follow a growing FTDC file or diagnostic.data directory",
+	Long: `Follow an FTDC file the way "tail -F" follows a log. If given a
+directory (typically mongod's diagnostic.data), it opens the newest
+metrics.* file, streams new chunks as they are flushed by mongod, and
+rotates to the next file as soon as one appears.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTail,
+}
+
+func init() {
+	rootCmd.AddCommand(tailCmd)
+	tailCmd.Flags().StringVar(&tailFrom, "from", "", "Backfill samples from this far back before following (e.g. 10m)")
+	tailCmd.Flags().StringVar(&tailFormat, "format", "jsonl", "Output format: jsonl or csv")
+	tailCmd.Flags().StringVar(&tailMetrics, "metrics", "", "Comma-separated glob patterns restricting which metrics are emitted")
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	info, err := os.Stat(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", args[0], err)
+	}
+
+	mf := newMetricFilter(tailMetrics)
+
+	var backfill time.Duration
+	if tailFrom != "" {
+		backfill, err = time.ParseDuration(tailFrom)
+		if err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	emit := newTailEmitter(w, tailFormat)
+
+	if info.IsDir() {
+		return tailDirectory(args[0], backfill, mf, emit)
+	}
+	return tailFileForever(args[0], mf, emit)
+}
+
+// tailEmitter writes one flattened sample at a time in the requested format.
+type tailEmitter func(map[string]interface{}) error
+
+func newTailEmitter(w *bufio.Writer, format string) tailEmitter {
+	switch format {
+	case "csv":
+		var header []string
+		wroteHeader := false
+		return func(doc map[string]interface{}) error {
+			if !wroteHeader {
+				header = sortedKeys(doc)
+				fmt.Fprintln(w, "time,"+strings.Join(header, ","))
+				wroteHeader = true
+			}
+			row := make([]string, 0, len(header)+1)
+			row = append(row, fmt.Sprintf("%v", doc["time"]))
+			for _, k := range header {
+				row = append(row, fmt.Sprintf("%v", doc[k]))
+			}
+			_, err := fmt.Fprintln(w, strings.Join(row, ","))
+			return err
+		}
+	default:
+		enc := json.NewEncoder(w)
+		return func(doc map[string]interface{}) error {
+			return enc.Encode(doc)
+		}
+	}
+}
+
+func sortedKeys(doc map[string]interface{}) []string {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		if k == "time" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metricsFilePrefix is the name prefix of the rolling FTDC files mongod
+// writes under diagnostic.data, e.g. metrics.2024-01-02T15-04-05Z-00000.
+const metricsFilePrefix = "metrics."
+
+// metricsFilePattern lists the metrics.* files in dir, in rotation order.
+func metricsFilePattern(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), metricsFilePrefix) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// tailDirectory follows a diagnostic.data directory: it backfills from the
+// newest file(s) covering the --from window, then follows the newest file,
+// rotating whenever fsnotify reports a new metrics.* file.
+func tailDirectory(dir string, backfill time.Duration, mf metricFilter, emit tailEmitter) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start directory watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	files, err := metricsFilePattern(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no metrics.* files found in %s", dir)
+	}
+
+	current := files[len(files)-1]
+	var currentOffset int64
+
+	if backfill > 0 {
+		cutoff := time.Now().Add(-backfill)
+		tf := timeFilter{start: cutoff}
+		for _, path := range files {
+			n, err := tailFileOnce(path, mf, tf, emit)
+			if err != nil {
+				return err
+			}
+			if path == current {
+				// Resume following the active file right where the
+				// backfill left off, instead of re-emitting it.
+				currentOffset = n
+			}
+		}
+	}
+
+	for {
+		if err := tailFileUntilRotation(current, dir, watcher, mf, emit, currentOffset); err != nil {
+			return err
+		}
+
+		files, err := metricsFilePattern(dir)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		next := files[len(files)-1]
+		if next == current {
+			return fmt.Errorf("metrics file rotation event with no new file in %s", dir)
+		}
+		current = next
+		currentOffset = 0
+	}
+}
+
+// tailFileForever follows a single file path that is not part of a
+// diagnostic.data directory, exiting only on error.
+func tailFileForever(path string, mf metricFilter, emit tailEmitter) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	return tailFileUntilRotation(path, filepath.Dir(path), watcher, mf, emit, 0)
+}
+
+// tailCountingReader tracks how many bytes have been pulled from the
+// underlying reader, so tailFileUntilRotation can resume exactly where
+// the last clean read left off.
+type tailCountingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *tailCountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// tailFileOnce decodes every existing chunk of path (used for --from
+// backfill) without following further writes, and reports how many bytes
+// were consumed so the caller can resume from there.
+func tailFileOnce(path string, mf metricFilter, tf timeFilter, emit tailEmitter) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	cr := &tailCountingReader{r: f}
+	ctx := context.Background()
+	iter := ftdc.ReadChunks(ctx, cr)
+
+	for iter.Next() {
+		chunk := iter.Chunk()
+		idLabels := chunkIdentifierLabels(chunk)
+		for i := 0; i < chunk.Size(); i++ {
+			ts := sampleTimestamp(chunk, i)
+			if !tf.includes(ts) {
+				continue
+			}
+			doc := flattenSample(chunk, i, mf)
+			for k, v := range idLabels {
+				doc[k] = v
+			}
+			doc["time"] = ts.Format(rfc3339Milli)
+			if err := emit(doc); err != nil {
+				return cr.n, err
+			}
+		}
+	}
+
+	return cr.n, iter.Err()
+}
+
+// tailFileUntilRotation decodes chunks from path as they are flushed,
+// blocking on fsnotify write events, and returns once a sibling metrics.*
+// file is created (mongod rotating to a new one).
+//
+// Because a ChunkIterator's channel is closed for good the moment it sees
+// io.EOF, it cannot simply be reused across write events: every drain
+// below opens a fresh reader seeked to the offset left by the previous
+// drain and builds a new iterator on top of it. The offset only advances
+// past what was just read when that read ended in a clean EOF; a decode
+// error partway through the tail almost always means mongod is still
+// mid-flush, so the next write event retries from the same offset instead
+// of skipping over the incomplete chunk.
+func tailFileUntilRotation(path, dir string, watcher *fsnotify.Watcher, mf metricFilter, emit tailEmitter, startOffset int64) error {
+	offset := startOffset
+
+	drain := func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek %s: %w", path, err)
+		}
+
+		cr := &tailCountingReader{r: f}
+		ctx := context.Background()
+		iter := ftdc.ReadChunks(ctx, cr)
+
+		for iter.Next() {
+			chunk := iter.Chunk()
+			idLabels := chunkIdentifierLabels(chunk)
+			for i := 0; i < chunk.Size(); i++ {
+				doc := flattenSample(chunk, i, mf)
+				for k, v := range idLabels {
+					doc[k] = v
+				}
+				doc["time"] = sampleTimestamp(chunk, i).Format(rfc3339Milli)
+				if err := emit(doc); err != nil {
+					return err
+				}
+			}
+		}
+
+		if iter.Err() == nil {
+			offset += cr.n
+		}
+
+		return nil
+	}
+
+	if err := drain(); err != nil {
+		return err
+	}
+
+	for event := range watcher.Events {
+		if filepath.Dir(event.Name) != dir {
+			continue
+		}
+
+		isRotation := event.Op&fsnotify.Create != 0 &&
+			event.Name != path &&
+			strings.HasPrefix(filepath.Base(event.Name), metricsFilePrefix)
+		if isRotation {
+			return nil
+		}
+
+		if event.Name == path && event.Op&fsnotify.Write != 0 {
+			if err := drain(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mongodb/ftdc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyRepair bool
+	verifyOutput string
+)
+
+// repairChunkSize is the sample count at which the repair writer flushes a
+// freshly re-encoded chunk, matching the library's own streaming defaults.
+const repairChunkSize = 1000
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [ftdc-file]",
+	Short: "Check an FTDC file for corruption",
+	Long: `Decode every chunk of an FTDC file, confirming each chunk's declared
+sample count matches the number of values actually present and that the
+overall timestamp series never goes backwards. Reports the approximate
+byte offset of the first chunk that fails to decode, whether that's a
+truncated write, a malformed zlib stream, or anything else the decoder
+rejects.
+
+With --repair and --output, every sample from the chunks that decoded
+successfully before that point is re-encoded into a fresh, valid FTDC
+file, salvaging the rest of a capture left behind by a crashed mongod.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "Re-encode intact chunks to --output, dropping everything after the first corrupt one")
+	verifyCmd.Flags().StringVar(&verifyOutput, "output", "", "Destination file for --repair")
+}
+
+// countingReader tracks how many bytes have been pulled from the
+// underlying reader so corruption can be reported by approximate file
+// offset. Because ftdc.ReadChunks may buffer ahead of the logical chunk
+// boundary, offsets are reported as "at or after" rather than exact.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	if verifyRepair && verifyOutput == "" {
+		return fmt.Errorf("--repair requires --output")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open FTDC file: %w", err)
+	}
+	defer f.Close()
+
+	var repairWriter io.WriteCloser
+	if verifyRepair {
+		out, err := os.Create(verifyOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create --output: %w", err)
+		}
+		repairWriter = ftdc.NewWriterCollector(repairChunkSize, out)
+	}
+
+	cr := &countingReader{r: f}
+	ctx := context.Background()
+	iter := ftdc.ReadChunks(ctx, cr)
+
+	var (
+		chunkIdx     int
+		lastOffset   int64
+		lastTime     time.Time
+		corruptCount int
+	)
+
+	for {
+		offsetBefore := cr.n
+		if !iter.Next() {
+			break
+		}
+
+		chunk := iter.Chunk()
+		n := chunk.Size()
+
+		for _, m := range chunk.Metrics {
+			if len(m.Values) != n {
+				fmt.Printf("chunk %d (offset ~%d): metric %q has %d samples, header declares %d\n",
+					chunkIdx, offsetBefore, m.Key(), len(m.Values), n)
+				corruptCount++
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			ts := sampleTimestamp(chunk, i)
+			if !lastTime.IsZero() && ts.Before(lastTime) {
+				fmt.Printf("chunk %d (offset ~%d): timestamp %s is before previous timestamp %s\n",
+					chunkIdx, offsetBefore, ts.Format(rfc3339Milli), lastTime.Format(rfc3339Milli))
+				corruptCount++
+			}
+			lastTime = ts
+		}
+
+		if repairWriter != nil {
+			if err := rewriteChunk(chunk, repairWriter); err != nil {
+				return fmt.Errorf("failed to re-encode chunk %d for repair: %w", chunkIdx, err)
+			}
+		}
+
+		lastOffset = cr.n
+		chunkIdx++
+	}
+
+	if err := iter.Err(); err != nil {
+		fmt.Printf("chunk %d (offset ~%d): failed to decode: %v\n", chunkIdx, lastOffset, err)
+		corruptCount++
+	}
+
+	if repairWriter != nil {
+		if err := repairWriter.Close(); err != nil {
+			return fmt.Errorf("failed to finish --output: %w", err)
+		}
+		fmt.Printf("repair: wrote %d intact chunk(s) to %s, dropping everything after\n", chunkIdx, verifyOutput)
+	}
+
+	fmt.Printf("verified %d chunk(s), %d problem(s) found\n", chunkIdx, corruptCount)
+
+	if corruptCount > 0 && !verifyRepair {
+		return fmt.Errorf("%d problem(s) found", corruptCount)
+	}
+
+	return nil
+}
+
+// rewriteChunk feeds every sample document of an intact chunk through w,
+// which re-encodes them into new, valid FTDC chunks. This sidesteps
+// copying raw bytes, which isn't reliable: ReadChunks reads several chunks
+// ahead of whatever Next() just handed the caller, so there is no exact
+// byte range here that corresponds to exactly one chunk.
+func rewriteChunk(chunk *ftdc.Chunk, w io.Writer) error {
+	ctx := context.Background()
+	it := chunk.StructuredIterator(ctx)
+	defer it.Close()
+
+	for it.Next() {
+		data, err := it.Document().MarshalBSON()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}